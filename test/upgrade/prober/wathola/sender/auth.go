@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// Authenticator attests to, or authenticates with, a sink before an
+// EventSender delivers a cloud event to it, so wathola can probe
+// auth-enabled broker/trigger setups rather than only open ones.
+type Authenticator interface {
+	// Authenticate returns ce annotated as needed (e.g. with a signature
+	// extension) together with any transport-level Credentials the
+	// EventSender should apply when sending it.
+	Authenticate(ce cloudevents.Event) (cloudevents.Event, Credentials, error)
+}
+
+// Credentials are transport-level attributes an Authenticator wants applied
+// when an event is sent, e.g. an Authorization header.
+type Credentials struct {
+	Headers map[string]string
+}
+
+// noopAuthenticator is the Authenticator used when none is configured; it
+// leaves the event and transport untouched.
+type noopAuthenticator struct{}
+
+func (noopAuthenticator) Authenticate(ce cloudevents.Event) (cloudevents.Event, Credentials, error) {
+	return ce, Credentials{}, nil
+}
+
+// HMACAuthenticator signs the canonical structured-mode JSON encoding of an
+// event with HMAC-SHA256, the CloudEvents payload-signature pattern used by
+// webhook integrations. The signature is set both as a "signature"
+// CloudEvents extension, so it travels with the event through any
+// protocol, and as a ce-signature transport header for sinks that check it
+// without parsing the body.
+type HMACAuthenticator struct {
+	Secret []byte
+}
+
+func (h HMACAuthenticator) Authenticate(ce cloudevents.Event) (cloudevents.Event, Credentials, error) {
+	canonical, err := json.Marshal(ce)
+	if err != nil {
+		return ce, Credentials{}, fmt.Errorf("could not marshal event for signing: %w", err)
+	}
+	mac := hmac.New(sha256.New, h.Secret)
+	mac.Write(canonical)
+	signature := hex.EncodeToString(mac.Sum(nil))
+	if err := ce.SetExtension("signature", signature); err != nil {
+		return ce, Credentials{}, err
+	}
+	return ce, Credentials{Headers: map[string]string{"ce-signature": signature}}, nil
+}
+
+// TokenSource returns a bearer token to present to a sink, e.g. one backed
+// by a projected Kubernetes ServiceAccount token.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// OIDCAuthenticator injects a bearer token obtained from Tokens as the
+// Authorization header, for sinks that require OIDC authentication.
+type OIDCAuthenticator struct {
+	Tokens TokenSource
+}
+
+func (o OIDCAuthenticator) Authenticate(ce cloudevents.Event) (cloudevents.Event, Credentials, error) {
+	token, err := o.Tokens.Token(context.Background())
+	if err != nil {
+		return ce, Credentials{}, fmt.Errorf("could not obtain OIDC token: %w", err)
+	}
+	return ce, Credentials{Headers: map[string]string{
+		"Authorization": fmt.Sprintf("Bearer %s", token),
+	}}, nil
+}
+
+// rejectUnsupportedHeaders fails loudly when creds carries transport headers
+// (e.g. the Authorization header an OIDCAuthenticator returns) that
+// protocol has no way to attach to an outgoing message, rather than
+// silently sending the event unauthenticated. Signature-only Authenticators
+// like HMACAuthenticator are unaffected, since they authenticate through a
+// CloudEvents extension on ce itself, which every protocol carries.
+func rejectUnsupportedHeaders(protocol string, creds Credentials) error {
+	if len(creds.Headers) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(creds.Headers))
+	for k := range creds.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fmt.Errorf(
+		"%s sender: authenticator requires transport headers %v, "+
+			"which aren't supported over this protocol", protocol, keys)
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func testEvent(t *testing.T) cloudevents.Event {
+	t.Helper()
+	return NewCloudEvent(struct{ Foo string }{Foo: "bar"}, "test.type", CodecJSON)
+}
+
+func TestHMACAuthenticatorSignsWithSecret(t *testing.T) {
+	secret := []byte("s3cr3t")
+	auth := HMACAuthenticator{Secret: secret}
+	ce := testEvent(t)
+
+	// Compute the expected signature from the event's canonical bytes
+	// before Authenticate mutates it by setting the extension, so this
+	// assertion doesn't just echo the implementation back at itself.
+	canonical, err := json.Marshal(ce)
+	if err != nil {
+		t.Fatalf("could not marshal event: %v", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(canonical)
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	signed, creds, err := auth.Authenticate(ce)
+	if err != nil {
+		t.Fatalf("Authenticate() returned error: %v", err)
+	}
+
+	sigExt, ok := signed.Extensions()["signature"]
+	if !ok {
+		t.Fatal("signed event is missing the signature extension")
+	}
+	if sigExt != want {
+		t.Fatalf("signature extension = %v, want %v", sigExt, want)
+	}
+	if creds.Headers["ce-signature"] != want {
+		t.Fatalf("ce-signature header = %v, want %v", creds.Headers["ce-signature"], want)
+	}
+}
+
+func TestHMACAuthenticatorDifferentSecretsDisagree(t *testing.T) {
+	base := testEvent(t)
+
+	// Deep-copy the identical event for each Authenticator so the two
+	// signatures can only differ because of the secret, not because
+	// Authenticate mutates its argument's extensions in place.
+	_, credsA, err := (HMACAuthenticator{Secret: []byte("secret-a")}).Authenticate(base.Clone())
+	if err != nil {
+		t.Fatalf("Authenticate() returned error: %v", err)
+	}
+	_, credsB, err := (HMACAuthenticator{Secret: []byte("secret-b")}).Authenticate(base.Clone())
+	if err != nil {
+		t.Fatalf("Authenticate() returned error: %v", err)
+	}
+
+	if credsA.Headers["ce-signature"] == credsB.Headers["ce-signature"] {
+		t.Fatal("events signed with different secrets produced the same signature")
+	}
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestOIDCAuthenticatorSetsBearerHeader(t *testing.T) {
+	auth := OIDCAuthenticator{Tokens: staticTokenSource("my-token")}
+	_, creds, err := auth.Authenticate(testEvent(t))
+	if err != nil {
+		t.Fatalf("Authenticate() returned error: %v", err)
+	}
+	if got, want := creds.Headers["Authorization"], "Bearer my-token"; got != want {
+		t.Fatalf("Authorization header = %q, want %q", got, want)
+	}
+}
+
+type erroringTokenSource struct{}
+
+func (erroringTokenSource) Token(context.Context) (string, error) {
+	return "", errors.New("no token available")
+}
+
+func TestOIDCAuthenticatorPropagatesTokenError(t *testing.T) {
+	auth := OIDCAuthenticator{Tokens: erroringTokenSource{}}
+	if _, _, err := auth.Authenticate(testEvent(t)); err == nil {
+		t.Fatal("expected an error when the token source fails, got nil")
+	}
+}
+
+func TestRejectUnsupportedHeaders(t *testing.T) {
+	if err := rejectUnsupportedHeaders("kafka", Credentials{}); err != nil {
+		t.Fatalf("expected no error for empty Credentials, got: %v", err)
+	}
+	creds := Credentials{Headers: map[string]string{"Authorization": "Bearer x"}}
+	if err := rejectUnsupportedHeaders("kafka", creds); err == nil {
+		t.Fatal("expected an error when Credentials carry headers, got nil")
+	}
+}
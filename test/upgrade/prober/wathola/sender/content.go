@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+
+// ContentMode selects how a CloudEvent is framed on the wire.
+type ContentMode string
+
+const (
+	// ContentModeBinary carries the CloudEvents attributes as transport
+	// headers and the data as the raw body. It's the default, matching
+	// the sender's historical behaviour.
+	ContentModeBinary ContentMode = "binary"
+	// ContentModeStructured carries the whole event, attributes and data,
+	// as a single application/cloudevents+json document.
+	ContentModeStructured ContentMode = "structured"
+	// ContentModeBatched wraps the event in a single-element
+	// application/cloudevents-batch+json array.
+	ContentModeBatched ContentMode = "batched"
+)
+
+// Codec selects the media type used to encode an event's data payload, i.e.
+// the value passed to cloudevents.Event.SetData. Only CodecJSON is
+// implemented today: SetData only knows how to marshal a Go value itself
+// for "...json" content types, so picking anything else would either error
+// out of SetData or mislabel JSON bytes with the wrong content type.
+//
+// NOTE: "alternate data codecs (protobuf, Avro)" was part of this package's
+// original configurable-content-mode backlog item, but is intentionally not
+// delivered here -- it's a partial implementation of that item's scope, not
+// an oversight. Protobuf/Avro support needs real marshaling via the
+// sdk-go/v2 event format registry before another Codec can be added here.
+type Codec string
+
+const (
+	// CodecJSON is the sender's historical default, and currently the
+	// only supported codec.
+	CodecJSON Codec = "application/json"
+)
+
+// encoding maps a ContentMode onto the protocol/http encoding it corresponds
+// to, defaulting unknown or empty modes to binary.
+func (m ContentMode) encoding() cehttp.Encoding {
+	switch m {
+	case ContentModeStructured:
+		return cehttp.StructuredV1
+	case ContentModeBatched:
+		return cehttp.BatchedV1
+	default:
+		return cehttp.BinaryV1
+	}
+}
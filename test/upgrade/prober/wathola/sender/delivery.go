@@ -0,0 +1,102 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// DeliveryPolicy controls how a step recorded in the outbox is retried
+// before it is either acknowledged by the sink or given up on and moved to
+// the dead-letter path.
+type DeliveryPolicy struct {
+	// MaxAttempts is the number of times SendEvent is tried for a single
+	// outbox entry before it's deadlettered. Zero means retry forever.
+	MaxAttempts int
+	// BackoffBase is the delay before the first retry. Every subsequent
+	// retry doubles the previous delay, plus jitter, up to BackoffCap.
+	BackoffBase time.Duration
+	// BackoffCap caps the backoff delay computed from BackoffBase.
+	BackoffCap time.Duration
+	// AttemptTimeout bounds a single SendEvent call. Zero means no
+	// per-attempt timeout is applied.
+	AttemptTimeout time.Duration
+	// DeadLetterPath is the file that undeliverable steps are appended to,
+	// as newline-delimited JSON-encoded CloudEvents.
+	DeadLetterPath string
+}
+
+// DefaultDeliveryPolicy is used when a sender's delivery policy is left zero.
+// The policy is not read from senderConfig.
+var DefaultDeliveryPolicy = DeliveryPolicy{
+	MaxAttempts:    8,
+	BackoffBase:    500 * time.Millisecond,
+	BackoffCap:     30 * time.Second,
+	AttemptTimeout: 10 * time.Second,
+	DeadLetterPath: "wathola-sender-deadletter.jsonl",
+}
+
+// backoff returns the delay to wait before the given attempt (1-indexed),
+// as exponential backoff off BackoffBase capped at BackoffCap, with up to
+// 20% jitter to avoid retry storms across many wathola senders.
+func (p DeliveryPolicy) backoff(attempt int) time.Duration {
+	d := p.BackoffBase << uint(attempt-1) //nolint:gosec
+	if d <= 0 || d > p.BackoffCap {
+		d = p.BackoffCap
+	}
+	jitter := time.Duration(rand.Int63n(int64(d) / 5+1)) //nolint:gosec
+	return d + jitter
+}
+
+// deliver retries send until it succeeds, attempts are exhausted, or ctx is
+// cancelled, recording metrics for every attempt it makes. Each attempt is
+// additionally bounded by p.AttemptTimeout, if set, via a context derived
+// from ctx.
+func (p DeliveryPolicy) deliver(ctx context.Context, send func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; p.MaxAttempts == 0 || attempt <= p.MaxAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		attemptCtx := ctx
+		cancel := func() {}
+		if p.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, p.AttemptTimeout)
+		}
+		deliveryMetrics.incAttempts()
+		deliveryMetrics.incInFlight()
+		err = send(attemptCtx)
+		deliveryMetrics.decInFlight()
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if p.MaxAttempts != 0 && attempt >= p.MaxAttempts {
+			log.Warnf("Delivery attempt %v failed: %v, giving up", attempt, err)
+			break
+		}
+		delay := p.backoff(attempt)
+		log.Warnf("Delivery attempt %v failed: %v, retrying in %v",
+			attempt, err, delay)
+		if !sleepOrDone(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+	deliveryMetrics.incDeadLettered()
+	return err
+}
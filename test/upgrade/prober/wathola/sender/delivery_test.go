@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeliveryPolicyDeliverRetriesUntilSuccess(t *testing.T) {
+	p := DeliveryPolicy{MaxAttempts: 3, BackoffBase: time.Millisecond, BackoffCap: time.Millisecond}
+	attempts := 0
+	err := p.deliver(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("deliver() returned error: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("deliver() made %v attempts, want 2", attempts)
+	}
+}
+
+func TestDeliveryPolicyDeliverStopsWhenContextCancelled(t *testing.T) {
+	p := DeliveryPolicy{MaxAttempts: 0, BackoffBase: time.Millisecond, BackoffCap: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	err := p.deliver(ctx, func(ctx context.Context) error {
+		attempts++
+		if attempts == 2 {
+			cancel()
+		}
+		return errors.New("always fails")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("deliver() returned %v, want context.Canceled", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("deliver() made %v attempts after cancellation, want 2", attempts)
+	}
+}
+
+func TestDeliveryPolicyDeliverAppliesAttemptTimeout(t *testing.T) {
+	p := DeliveryPolicy{
+		MaxAttempts:    1,
+		BackoffBase:    time.Millisecond,
+		BackoffCap:     time.Millisecond,
+		AttemptTimeout: time.Millisecond,
+	}
+	var deadlineSet bool
+	err := p.deliver(context.Background(), func(ctx context.Context) error {
+		_, deadlineSet = ctx.Deadline()
+		return errors.New("fails")
+	})
+	if err == nil {
+		t.Fatal("deliver() returned nil error, want the send error after exhausting attempts")
+	}
+	if !deadlineSet {
+		t.Fatal("send was called with a context carrying no deadline, want AttemptTimeout applied")
+	}
+}
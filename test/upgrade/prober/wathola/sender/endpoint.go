@@ -0,0 +1,102 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"encoding/json"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// EventSender is a protocol binding capable of delivering a cloud event to
+// one kind of endpoint. Register makes an EventSender available to SendEvent,
+// which picks the first registered EventSender whose Supports returns true
+// for the configured endpoint.
+type EventSender interface {
+	// Supports reports whether this EventSender knows how to dial the
+	// given endpoint. Endpoint is typically one of the *Endpoint structs
+	// declared alongside the built-in senders, but implementations are
+	// free to accept plain values (e.g. httpSender accepts a string URL).
+	Supports(endpoint interface{}) bool
+	// SendEvent delivers ce to endpoint and returns a non-nil error if it
+	// wasn't acknowledged by the sink. ctx bounds the attempt and is
+	// cancelled on shutdown or when DeliveryPolicy.AttemptTimeout elapses.
+	SendEvent(ctx context.Context, ce cloudevents.Event, endpoint interface{}) error
+	// Authenticator returns the Authenticator this EventSender applies to
+	// every event it sends, so every protocol binding can carry the same
+	// credentials.
+	Authenticator() Authenticator
+}
+
+// KafkaEndpoint targets a Kafka topic reachable through the CloudEvents
+// Kafka protocol binding.
+type KafkaEndpoint struct {
+	Brokers []string `json:"brokers" yaml:"brokers"`
+	Topic   string   `json:"topic" yaml:"topic"`
+}
+
+// NatsEndpoint targets a NATS (or NATS JetStream) subject reachable through
+// the CloudEvents NATS protocol binding.
+type NatsEndpoint struct {
+	URL     string `json:"url" yaml:"url"`
+	Subject string `json:"subject" yaml:"subject"`
+}
+
+// Address is the tagged union that senderConfig.Address decodes into,
+// letting operators pick the protocol binding straight from wathola config
+// instead of only ever targeting an http(s) URL. Exactly one of HTTP, Kafka
+// or Nats should be set.
+type Address struct {
+	HTTP  string         `json:"http,omitempty" yaml:"http,omitempty"`
+	Kafka *KafkaEndpoint `json:"kafka,omitempty" yaml:"kafka,omitempty"`
+	Nats  *NatsEndpoint  `json:"nats,omitempty" yaml:"nats,omitempty"`
+}
+
+// Endpoint returns the concrete endpoint Address selects, suitable for
+// passing to SendEvent.
+func (a Address) Endpoint() interface{} {
+	switch {
+	case a.Kafka != nil:
+		return *a.Kafka
+	case a.Nats != nil:
+		return *a.Nats
+	default:
+		return a.HTTP
+	}
+}
+
+// resolveEndpoint normalizes senderConfig.Address into a concrete endpoint
+// value. A bare string (the sender's historical address format) and
+// KafkaEndpoint/NatsEndpoint values pass through unchanged; anything else --
+// in practice the map[string]interface{} that decoding YAML into an
+// interface{} field produces -- is decoded as an Address tagged union.
+func resolveEndpoint(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case string, KafkaEndpoint, NatsEndpoint:
+		return v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var addr Address
+		if err := json.Unmarshal(data, &addr); err != nil {
+			return v
+		}
+		return addr.Endpoint()
+	}
+}
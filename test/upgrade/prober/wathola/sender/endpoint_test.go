@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveEndpointPassesThroughBareString(t *testing.T) {
+	got := resolveEndpoint("http://example.com")
+	if got != "http://example.com" {
+		t.Fatalf("resolveEndpoint() = %#v, want unchanged string", got)
+	}
+}
+
+func TestResolveEndpointDecodesMapIntoKafkaEndpoint(t *testing.T) {
+	// This is the shape YAML decoding into an interface{} field actually
+	// produces, which is why resolveEndpoint has to round-trip through JSON
+	// rather than type-asserting straight to Address.
+	raw := map[string]interface{}{
+		"kafka": map[string]interface{}{
+			"brokers": []interface{}{"broker-1:9092", "broker-2:9092"},
+			"topic":   "wathola-events",
+		},
+	}
+	want := KafkaEndpoint{Brokers: []string{"broker-1:9092", "broker-2:9092"}, Topic: "wathola-events"}
+
+	got := resolveEndpoint(raw)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolveEndpoint() = %#v, want %#v", got, want)
+	}
+}
+
+func TestResolveEndpointDecodesMapIntoNatsEndpoint(t *testing.T) {
+	raw := map[string]interface{}{
+		"nats": map[string]interface{}{
+			"url":     "nats://nats.default.svc:4222",
+			"subject": "wathola-events",
+		},
+	}
+	want := NatsEndpoint{URL: "nats://nats.default.svc:4222", Subject: "wathola-events"}
+
+	got := resolveEndpoint(raw)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolveEndpoint() = %#v, want %#v", got, want)
+	}
+}
+
+func TestResolveEndpointFallsBackToRawValueOnMarshalFailure(t *testing.T) {
+	// func values can't be marshaled to JSON; resolveEndpoint should hand
+	// the raw value back unchanged rather than losing it. (reflect.DeepEqual
+	// can't be used here: it never considers two non-nil funcs equal, even
+	// the same one, so the assertion instead checks a marker field survived.)
+	raw := map[string]interface{}{"marker": "raw-value", "unmarshalable": func() {}}
+
+	got, ok := resolveEndpoint(raw).(map[string]interface{})
+	if !ok {
+		t.Fatalf("resolveEndpoint() = %#v (%T), want the raw map back unchanged", got, got)
+	}
+	if got["marker"] != "raw-value" {
+		t.Fatalf("resolveEndpoint() lost data from the raw value, got %#v", got)
+	}
+}
+
+func TestKafkaSenderSupports(t *testing.T) {
+	k := NewKafkaSender(nil)
+	if !k.Supports(KafkaEndpoint{Topic: "t"}) {
+		t.Fatal("Supports(KafkaEndpoint) = false, want true")
+	}
+	if k.Supports(NatsEndpoint{}) || k.Supports("http://example.com") {
+		t.Fatal("Supports() = true for a non-KafkaEndpoint value, want false")
+	}
+}
+
+func TestNatsSenderSupports(t *testing.T) {
+	n := NewNatsSender(nil)
+	if !n.Supports(NatsEndpoint{Subject: "s"}) {
+		t.Fatal("Supports(NatsEndpoint) = false, want true")
+	}
+	if n.Supports(KafkaEndpoint{}) || n.Supports("http://example.com") {
+		t.Fatal("Supports() = true for a non-NatsEndpoint value, want false")
+	}
+}
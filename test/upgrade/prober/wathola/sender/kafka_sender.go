@@ -0,0 +1,77 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol/kafka_sarama"
+)
+
+// kafkaSender delivers cloud events to a Kafka topic using the CloudEvents
+// Kafka protocol binding. SendContinually registers it, after the HTTP
+// sender and with the same Authenticator, and SendEvent picks it when the
+// wathola config targets a KafkaEndpoint.
+type kafkaSender struct {
+	authenticator Authenticator
+}
+
+// NewKafkaSender creates an EventSender that publishes to KafkaEndpoint
+// addresses, authenticating events with auth (or not at all if auth is nil).
+func NewKafkaSender(auth Authenticator) EventSender {
+	if auth == nil {
+		auth = noopAuthenticator{}
+	}
+	return kafkaSender{authenticator: auth}
+}
+
+func (k kafkaSender) Supports(endpoint interface{}) bool {
+	_, ok := endpoint.(KafkaEndpoint)
+	return ok
+}
+
+func (k kafkaSender) Authenticator() Authenticator {
+	return k.authenticator
+}
+
+func (k kafkaSender) SendEvent(ctx context.Context, ce cloudevents.Event, endpoint interface{}) error {
+	ce, creds, err := k.authenticator.Authenticate(ce)
+	if err != nil {
+		return err
+	}
+	if err := rejectUnsupportedHeaders("kafka", creds); err != nil {
+		return err
+	}
+	kafkaEndpoint := endpoint.(KafkaEndpoint)
+	protocol, err := kafka_sarama.NewSender(
+		kafkaEndpoint.Brokers, sarama.NewConfig(), kafkaEndpoint.Topic)
+	if err != nil {
+		return err
+	}
+	defer protocol.Close(context.Background())
+
+	c, err := cloudevents.NewClient(protocol)
+	if err != nil {
+		return err
+	}
+	result := c.Send(ctx, ce)
+	if cloudevents.IsACK(result) {
+		return nil
+	}
+	return result
+}
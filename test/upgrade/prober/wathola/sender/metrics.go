@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import "sync/atomic"
+
+// deliveryMetrics tracks the outbox's delivery activity so the upgrade test
+// can assert on it instead of relying on time.Sleep(Cooldown).
+var deliveryMetrics = &Metrics{}
+
+// Metrics exposes counters about outbox delivery activity. All fields are
+// safe for concurrent use.
+type Metrics struct {
+	attempts     int64
+	inFlight     int64
+	deadLettered int64
+}
+
+// Attempts returns the total number of SendEvent attempts made so far,
+// including retries.
+func (m *Metrics) Attempts() int64 { return atomic.LoadInt64(&m.attempts) }
+
+// InFlight returns the number of SendEvent calls currently outstanding.
+func (m *Metrics) InFlight() int64 { return atomic.LoadInt64(&m.inFlight) }
+
+// DeadLettered returns the number of outbox entries that exhausted their
+// DeliveryPolicy and were moved to the dead-letter path.
+func (m *Metrics) DeadLettered() int64 { return atomic.LoadInt64(&m.deadLettered) }
+
+func (m *Metrics) incAttempts()     { atomic.AddInt64(&m.attempts, 1) }
+func (m *Metrics) incInFlight()     { atomic.AddInt64(&m.inFlight, 1) }
+func (m *Metrics) decInFlight()     { atomic.AddInt64(&m.inFlight, -1) }
+func (m *Metrics) incDeadLettered() { atomic.AddInt64(&m.deadLettered, 1) }
+
+// DeliveryMetrics returns the process-wide delivery metrics.
+func DeliveryMetrics() *Metrics {
+	return deliveryMetrics
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol/nats"
+)
+
+// natsSender delivers cloud events to a NATS (or NATS JetStream) subject
+// using the CloudEvents NATS protocol binding. SendContinually registers it,
+// after the HTTP sender and with the same Authenticator, and SendEvent picks
+// it when the wathola config targets a NatsEndpoint.
+type natsSender struct {
+	authenticator Authenticator
+}
+
+// NewNatsSender creates an EventSender that publishes to NatsEndpoint
+// addresses, authenticating events with auth (or not at all if auth is nil).
+func NewNatsSender(auth Authenticator) EventSender {
+	if auth == nil {
+		auth = noopAuthenticator{}
+	}
+	return natsSender{authenticator: auth}
+}
+
+func (n natsSender) Supports(endpoint interface{}) bool {
+	_, ok := endpoint.(NatsEndpoint)
+	return ok
+}
+
+func (n natsSender) Authenticator() Authenticator {
+	return n.authenticator
+}
+
+func (n natsSender) SendEvent(ctx context.Context, ce cloudevents.Event, endpoint interface{}) error {
+	ce, creds, err := n.authenticator.Authenticate(ce)
+	if err != nil {
+		return err
+	}
+	if err := rejectUnsupportedHeaders("nats", creds); err != nil {
+		return err
+	}
+	natsEndpoint := endpoint.(NatsEndpoint)
+	protocol, err := nats.NewSender(natsEndpoint.URL, natsEndpoint.Subject, nats.NatsOptions())
+	if err != nil {
+		return err
+	}
+	defer protocol.Close(context.Background())
+
+	c, err := cloudevents.NewClient(protocol)
+	if err != nil {
+		return err
+	}
+	result := c.Send(ctx, ce)
+	if cloudevents.IsACK(result) {
+		return nil
+	}
+	return result
+}
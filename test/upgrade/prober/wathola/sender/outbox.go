@@ -0,0 +1,132 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+var pendingBucket = []byte("pending")
+
+// outbox is a persistent write-ahead log of steps that have been generated
+// but not yet acknowledged by the sink. Entries are written before SendEvent
+// is attempted and removed once it succeeds, so a crash or SIGTERM between
+// those two points is recovered by Replay on the next start, instead of the
+// step silently vanishing.
+type outbox struct {
+	db *bolt.DB
+}
+
+// openOutbox opens (creating if necessary) the outbox WAL file at path.
+func openOutbox(path string) (*outbox, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(pendingBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &outbox{db: db}, nil
+}
+
+// Put persists ce as pending, keyed by its CloudEvents ID.
+func (o *outbox) Put(ce cloudevents.Event) error {
+	data, err := json.Marshal(ce)
+	if err != nil {
+		return err
+	}
+	return o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Put([]byte(ce.ID()), data)
+	})
+}
+
+// Delivered removes id from the outbox, marking it as acknowledged.
+func (o *outbox) Delivered(id string) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Delete([]byte(id))
+	})
+}
+
+// Replay calls fn once for every entry still pending, in key (and therefore
+// creation) order, removing it from the outbox once fn returns nil. It's
+// meant to be called once on startup, before SendContinually begins
+// generating new steps, so that no step number is skipped or resent twice.
+// Entries are deleted one at a time as fn succeeds for them, rather than in
+// the same transaction that lists them, so a failure part-way through
+// leaves the remaining entries pending for the next Replay. Replay stops and
+// returns ctx.Err() as soon as ctx is cancelled, leaving every entry it
+// hasn't reached yet pending, so a SIGTERM during a long replay doesn't ride
+// out the remaining queue.
+func (o *outbox) Replay(ctx context.Context, fn func(ce cloudevents.Event) error) error {
+	var pending []cloudevents.Event
+	err := o.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).ForEach(func(_, data []byte) error {
+			var ce cloudevents.Event
+			if err := json.Unmarshal(data, &ce); err != nil {
+				return err
+			}
+			pending = append(pending, ce)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, ce := range pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fn(ce); err != nil {
+			return err
+		}
+		if err := o.Delivered(ce.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying WAL file.
+func (o *outbox) Close() error {
+	return o.db.Close()
+}
+
+// deadLetter appends ce, that exhausted its DeliveryPolicy, to path as a
+// single line of JSON so it can be inspected or replayed manually later.
+func deadLetter(ce cloudevents.Event, path string) error {
+	data, err := json.Marshal(ce)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
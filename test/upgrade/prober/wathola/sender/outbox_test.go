@@ -0,0 +1,159 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func openTestOutbox(t *testing.T) *outbox {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	ob, err := openOutbox(path)
+	if err != nil {
+		t.Fatalf("openOutbox() returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = ob.Close() })
+	return ob
+}
+
+func TestOutboxReplayDeliversPendingEntriesAndClearsThem(t *testing.T) {
+	ob := openTestOutbox(t)
+	first := testEvent(t)
+	second := NewCloudEvent(struct{ Foo string }{Foo: "baz"}, "test.type", CodecJSON)
+
+	if err := ob.Put(first); err != nil {
+		t.Fatalf("Put(first) returned error: %v", err)
+	}
+	if err := ob.Put(second); err != nil {
+		t.Fatalf("Put(second) returned error: %v", err)
+	}
+
+	var replayed []string
+	err := ob.Replay(context.Background(), func(ce cloudevents.Event) error {
+		replayed = append(replayed, ce.ID())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() returned error: %v", err)
+	}
+	if len(replayed) != 2 {
+		t.Fatalf("Replay() delivered %v entries, want 2", len(replayed))
+	}
+
+	// Replaying again should find nothing, since a nil-returning fn should
+	// have cleared every entry it was handed.
+	var again []string
+	err = ob.Replay(context.Background(), func(ce cloudevents.Event) error {
+		again = append(again, ce.ID())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("second Replay() returned error: %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("second Replay() delivered %v entries, want 0", len(again))
+	}
+}
+
+func TestOutboxDeliveredRemovesEntryBeforeReplay(t *testing.T) {
+	ob := openTestOutbox(t)
+	ce := testEvent(t)
+	if err := ob.Put(ce); err != nil {
+		t.Fatalf("Put() returned error: %v", err)
+	}
+	if err := ob.Delivered(ce.ID()); err != nil {
+		t.Fatalf("Delivered() returned error: %v", err)
+	}
+
+	var replayed []string
+	err := ob.Replay(context.Background(), func(ce cloudevents.Event) error {
+		replayed = append(replayed, ce.ID())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() returned error: %v", err)
+	}
+	if len(replayed) != 0 {
+		t.Fatalf("Replay() delivered %v entries after Delivered(), want 0", len(replayed))
+	}
+}
+
+func TestOutboxReplayLeavesLaterEntriesPendingOnError(t *testing.T) {
+	ob := openTestOutbox(t)
+	first := testEvent(t)
+	second := NewCloudEvent(struct{ Foo string }{Foo: "baz"}, "test.type", CodecJSON)
+	if err := ob.Put(first); err != nil {
+		t.Fatalf("Put(first) returned error: %v", err)
+	}
+	if err := ob.Put(second); err != nil {
+		t.Fatalf("Put(second) returned error: %v", err)
+	}
+
+	failFirst := errors.New("delivery failed")
+	calls := 0
+	err := ob.Replay(context.Background(), func(ce cloudevents.Event) error {
+		calls++
+		return failFirst
+	})
+	if !errors.Is(err, failFirst) {
+		t.Fatalf("Replay() returned %v, want %v", err, failFirst)
+	}
+	if calls != 1 {
+		t.Fatalf("Replay() called fn %v times before stopping, want 1", calls)
+	}
+
+	// Both entries, including the one fn never reached, must still be
+	// pending: the failure must not roll back or skip past either of them.
+	var remaining []string
+	err = ob.Replay(context.Background(), func(ce cloudevents.Event) error {
+		remaining = append(remaining, ce.ID())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() returned error: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("Replay() after failure delivered %v entries, want 2", len(remaining))
+	}
+}
+
+func TestOutboxReplayStopsWhenContextCancelled(t *testing.T) {
+	ob := openTestOutbox(t)
+	if err := ob.Put(testEvent(t)); err != nil {
+		t.Fatalf("Put() returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := ob.Replay(ctx, func(ce cloudevents.Event) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Replay() returned %v, want context.Canceled", err)
+	}
+	if calls != 0 {
+		t.Fatalf("Replay() called fn %v times on an already-cancelled context, want 0", calls)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2020-2021 The Knative Authors
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sender
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// runWorkerPool drives s.concurrency workers (at least one) that each
+// allocate step numbers from a single atomic counter and deliver them
+// through s.sendStep, optionally throttled in aggregate to s.targetRate
+// events/sec. When no targetRate is configured, each worker instead paces
+// itself with senderConfig.Interval/Cooldown, matching the sender's
+// historical single-worker behaviour. It blocks until ctx is cancelled and
+// every in-flight delivery has returned, then reports how many steps were
+// acknowledged.
+func (s *sender) runWorkerPool(ctx context.Context) int {
+	workers := s.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var limiter *rate.Limiter
+	if s.targetRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(s.targetRate), workers)
+	}
+
+	var stepNumber int64
+	var acked int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func(worker int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				if limiter != nil {
+					if err := limiter.Wait(ctx); err != nil {
+						return
+					}
+				}
+				number := int(atomic.AddInt64(&stepNumber, 1))
+				if err := s.sendStep(ctx, number); err != nil {
+					log.Warnf("Worker %v could not send step event #%v: %v",
+						worker, number, err)
+					if limiter == nil && !sleepOrDone(ctx, senderConfig.Cooldown) {
+						return
+					}
+					continue
+				}
+				atomic.AddInt64(&acked, 1)
+				if limiter == nil && !sleepOrDone(ctx, senderConfig.Interval) {
+					return
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	return int(atomic.LoadInt64(&acked))
+}
+
+// sleepOrDone pauses for d, reporting false without sleeping the full
+// duration if ctx is cancelled first, so a worker's own pacing never delays
+// shutdown. A non-positive d returns true immediately.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -15,14 +15,16 @@
 
 package sender
 
-import "C"
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/wavesoftware/go-ensure"
 	"knative.dev/eventing/test/upgrade/prober/wathola/config"
 	"knative.dev/eventing/test/upgrade/prober/wathola/event"
@@ -33,6 +35,21 @@ import (
 	"time"
 )
 
+// defaultOutboxPath is where the outbox WAL is kept. In the full deployment
+// this would be sourced from senderConfig, alongside DeliveryPolicy.
+const defaultOutboxPath = "wathola-sender-outbox.db"
+
+// sourceURI is resolved once at startup rather than inside NewCloudEvent, so
+// that building an event has no I/O side effect and its canonical bytes are
+// stable for an Authenticator to sign.
+var sourceURI = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("knative://%s/wathola/sender", host)
+}()
+
 var (
 	// ErrEndpointTypeNotSupported is raised if configured endpoint isn't
 	// supported by any of the event senders that are registered.
@@ -44,49 +61,97 @@ var (
 )
 
 type sender struct {
-	counter int
+	counter       int
+	outbox        *outbox
+	delivery      DeliveryPolicy
+	format        ContentMode
+	codec         Codec
+	concurrency   int
+	targetRate    float64
+	tlsConfig     *tls.Config
+	authenticator Authenticator
 }
 
 func (s *sender) SendContinually() {
-	var shutdownCh = make(chan struct{})
-	defer s.sendFinished()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ob, err := openOutbox(defaultOutboxPath)
+	ensure.NoError(err)
+	s.outbox = ob
+	defer s.outbox.Close()
+	if s.delivery == (DeliveryPolicy{}) {
+		s.delivery = DefaultDeliveryPolicy
+	}
+	if s.format == "" {
+		s.format = ContentModeBinary
+	}
+	if s.codec == "" {
+		s.codec = CodecJSON
+	}
+	if s.concurrency == 0 {
+		s.concurrency = senderConfig.Concurrency
+	}
+	if s.concurrency == 0 {
+		s.concurrency = 1
+	}
+	if s.targetRate == 0 {
+		s.targetRate = senderConfig.TargetRate
+	}
+	if s.authenticator == nil {
+		s.authenticator = noopAuthenticator{}
+	}
+	Register(NewHTTPSender(s.format, s.tlsConfig, s.authenticator))
+	Register(NewKafkaSender(s.authenticator))
+	Register(NewNatsSender(s.authenticator))
 
+	// Installed before Replay runs, so a sink that's down during startup
+	// replay still honours SIGTERM instead of riding out the full retry
+	// budget with the shutdown signal unheard.
 	go func() {
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 		sig := <-c
 		// sig is a ^C or term, handle it
 		log.Infof("%v signal received, closing", sig.String())
-		close(shutdownCh)
+		cancel()
 	}()
 
-	for {
-		select {
-		case <-shutdownCh:
-			return
-		default:
-		}
-		err := s.sendStep()
-		if err != nil {
-			log.Warnf("Could not send step event, retry in %v", senderConfig.Cooldown)
-			time.Sleep(senderConfig.Cooldown)
-		} else {
-			time.Sleep(senderConfig.Interval)
+	err = s.outbox.Replay(ctx, func(ce cloudevents.Event) error {
+		log.Infof("Replaying undelivered event %v from outbox", ce.ID())
+		err := s.delivery.deliver(ctx, func(ctx context.Context) error {
+			return SendEvent(ctx, ce, endpoint())
+		})
+		if err == nil || ctx.Err() != nil {
+			return err
 		}
+		// Attempts are exhausted: once ce is on the dead-letter path, Replay
+		// clears it from the outbox and goes on with the next entry.
+		return deadLetter(ce, s.delivery.DeadLetterPath)
+	})
+	if err != nil {
+		log.Warnf("Could not replay outbox, undelivered events remain queued: %v", err)
 	}
+
+	// Uses its own context rather than ctx: even when shutdown was what
+	// stopped runWorkerPool, the finished notification still deserves a
+	// best-effort delivery attempt instead of being skipped outright because
+	// ctx is already cancelled.
+	defer s.sendFinished(context.Background())
+
+	s.counter = s.runWorkerPool(ctx)
 }
 
-// NewCloudEvent creates a new cloud event
-func NewCloudEvent(data interface{}, typ string) cloudevents.Event {
+// NewCloudEvent creates a new cloud event, encoding data using codec. It has
+// no I/O side effects, so its output is safe to re-marshal for signing.
+func NewCloudEvent(data interface{}, typ string, codec Codec) cloudevents.Event {
 	e := cloudevents.NewEvent()
-	e.SetDataContentType("application/json")
+	e.SetDataContentType(string(codec))
 	e.SetType(typ)
-	host, err := os.Hostname()
-	ensure.NoError(err)
-	e.SetSource(fmt.Sprintf("knative://%s/wathola/sender", host))
+	e.SetSource(sourceURI)
 	e.SetID(NewEventID())
 	e.SetTime(time.Now())
-	err = e.SetData(cloudevents.ApplicationJSON, data)
+	err := e.SetData(string(codec), data)
 	ensure.NoError(err)
 	errs := e.Validate()
 	if errs != nil {
@@ -95,13 +160,24 @@ func NewCloudEvent(data interface{}, typ string) cloudevents.Event {
 	return e
 }
 
-// Register will register a EventSender to be used.
+// endpoint resolves senderConfig.Address -- the untyped value wathola
+// config decodes -- into the concrete endpoint value SendEvent dispatches
+// on, so operators can select http, kafka or nats straight from config.
+func endpoint() interface{} {
+	return resolveEndpoint(senderConfig.Address)
+}
+
+// Register will register a EventSender to be used. Senders are tried in
+// registration order, so more specific EventSenders should be registered
+// before more permissive ones.
 func Register(es EventSender) {
 	eventSenders = append(eventSenders, es)
 }
 
-// SendEvent will send cloud event to given url
-func SendEvent(ce cloudevents.Event, endpoint interface{}) error {
+// SendEvent sends ce to endpoint using the first registered EventSender
+// whose Supports accepts it, falling back to the built-in httpSender when
+// none have been registered yet. ctx bounds the send attempt.
+func SendEvent(ctx context.Context, ce cloudevents.Event, endpoint interface{}) error {
 	senders := make([]EventSender, len(eventSenders), len(eventSenders)+1)
 	senders = append(senders, eventSenders...)
 	if len(senders) == 0 {
@@ -109,13 +185,31 @@ func SendEvent(ce cloudevents.Event, endpoint interface{}) error {
 	}
 	for _, eventSender := range senders {
 		if eventSender.Supports(endpoint) {
-			return eventSender.SendEvent(ce, endpoint)
+			return eventSender.SendEvent(ctx, ce, endpoint)
 		}
 	}
 	return fmt.Errorf("%w: endpoint is %#v", ErrEndpointTypeNotSupported, endpoint)
 }
 
-type httpSender struct{}
+// httpSender is the built-in EventSender for http:// and https:// endpoints.
+// Its zero value sends unauthenticated binary-mode JSON over plain TLS
+// defaults, matching the sender's historical behaviour; NewHTTPSender lets
+// operators pick a different ContentMode, mTLS config and Authenticator.
+type httpSender struct {
+	format        ContentMode
+	tlsConfig     *tls.Config
+	authenticator Authenticator
+}
+
+// NewHTTPSender creates an EventSender for http(s):// endpoints that encodes
+// events using format, dials over tlsConfig (or Go's TLS defaults if nil)
+// and authenticates with auth (or not at all if auth is nil).
+func NewHTTPSender(format ContentMode, tlsConfig *tls.Config, auth Authenticator) EventSender {
+	if auth == nil {
+		auth = noopAuthenticator{}
+	}
+	return httpSender{format: format, tlsConfig: tlsConfig, authenticator: auth}
+}
 
 func (h httpSender) Supports(endpoint interface{}) bool {
 	switch url := endpoint.(type) {
@@ -127,41 +221,95 @@ func (h httpSender) Supports(endpoint interface{}) bool {
 	}
 }
 
-func (h httpSender) SendEvent(ce cloudevents.Event, endpoint interface{}) error {
+func (h httpSender) Authenticator() Authenticator {
+	return h.authenticator
+}
+
+func (h httpSender) SendEvent(ctx context.Context, ce cloudevents.Event, endpoint interface{}) error {
 	url := endpoint.(string)
-	c, err := cloudevents.NewDefaultClient()
+	auth := h.authenticator
+	if auth == nil {
+		auth = noopAuthenticator{}
+	}
+	ce, creds, err := auth.Authenticate(ce)
 	if err != nil {
 		return err
 	}
-	ctx := cloudevents.ContextWithTarget(context.Background(), url)
 
-	result := c.Send(ctx, ce)
+	var popts []cehttp.Option
+	if h.tlsConfig != nil {
+		popts = append(popts, cehttp.WithClient(http.Client{
+			Transport: &http.Transport{TLSClientConfig: h.tlsConfig},
+		}))
+	}
+	p, err := cehttp.New(popts...)
+	if err != nil {
+		return err
+	}
+	c, err := cloudevents.NewClient(p)
+	if err != nil {
+		return err
+	}
+
+	sendCtx := cloudevents.ContextWithTarget(ctx, url)
+	sendCtx = cehttp.WithEncoding(sendCtx, h.format.encoding())
+	for key, value := range creds.Headers {
+		sendCtx = cehttp.ContextWithHeader(sendCtx, key, value)
+	}
+
+	result := c.Send(sendCtx, ce)
 	if cloudevents.IsACK(result) {
 		return nil
 	}
 	return result
 }
 
-func (s *sender) sendStep() error {
-	step := event.Step{Number: s.counter + 1}
-	ce := NewCloudEvent(step, event.StepType)
-	endpoint := senderConfig.Address
-	log.Infof("Sending step event #%v to %v", step.Number, endpoint)
-	err := SendEvent(ce, endpoint)
-	if err != nil {
-		return err
-	}
-	s.counter++
-	return nil
+// sendStep delivers a single step event numbered number. number is allocated
+// by the caller (the worker pool's atomic counter), so concurrent workers
+// never reuse or skip a step number.
+func (s *sender) sendStep(ctx context.Context, number int) error {
+	step := event.Step{Number: number}
+	ce := NewCloudEvent(step, event.StepType, s.codec)
+	log.Infof("Sending step event #%v to %v", step.Number, endpoint())
+	return s.deliver(ctx, ce)
 }
 
-func (s *sender) sendFinished() {
+func (s *sender) sendFinished(ctx context.Context) {
 	if s.counter == 0 {
 		return
 	}
 	finished := event.Finished{Count: s.counter}
-	endpoint := senderConfig.Address
-	ce := NewCloudEvent(finished, event.FinishedType)
-	log.Infof("Sending finished event (count: %v) to %v", finished.Count, endpoint)
-	ensure.NoError(SendEvent(ce, endpoint))
+	ce := NewCloudEvent(finished, event.FinishedType, s.codec)
+	log.Infof("Sending finished event (count: %v) to %v", finished.Count, endpoint())
+	if err := s.deliver(ctx, ce); err != nil {
+		log.Warnf("Could not deliver finished event, it has been deadlettered: %v", err)
+	}
+}
+
+// deliver writes ce to the outbox before attempting delivery, retries it per
+// s.delivery (bounded by ctx, which is cancelled on shutdown), and either
+// clears it from the outbox on success or moves it to the dead-letter path
+// once attempts are genuinely exhausted, so a step is never silently lost
+// from the outbox's perspective. If ctx is cancelled mid-retry, ce is left
+// in the outbox untouched -- neither dead-lettered nor cleared -- so the
+// next Replay picks it back up instead of it vanishing on shutdown.
+func (s *sender) deliver(ctx context.Context, ce cloudevents.Event) error {
+	if err := s.outbox.Put(ce); err != nil {
+		return fmt.Errorf("could not persist %v to outbox: %w", ce.ID(), err)
+	}
+	err := s.delivery.deliver(ctx, func(ctx context.Context) error {
+		return SendEvent(ctx, ce, endpoint())
+	})
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	if err != nil {
+		if dlErr := deadLetter(ce, s.delivery.DeadLetterPath); dlErr != nil {
+			log.Warnf("Could not write %v to dead-letter path: %v", ce.ID(), dlErr)
+		}
+	}
+	if dErr := s.outbox.Delivered(ce.ID()); dErr != nil {
+		log.Warnf("Could not clear %v from outbox: %v", ce.ID(), dErr)
+	}
+	return err
 }